@@ -0,0 +1,162 @@
+package pgext
+
+import (
+	"context"
+	"strings"
+
+	"github.com/go-pg/pg/v10"
+	"github.com/go-pg/pg/v10/orm"
+
+	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace"
+	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace/ext"
+	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace/tracer"
+)
+
+type ddSpanKey struct{}
+
+// DatadogHook is a pg.QueryHook that traces queries with dd-trace-go.
+type DatadogHook struct {
+	serviceName  string
+	rawStatement bool
+}
+
+var _ pg.QueryHook = (*DatadogHook)(nil)
+
+// DDOption configures a DatadogHook.
+type DDOption func(*DatadogHook)
+
+// WithServiceName sets the service name reported for spans. The default is
+// "postgres.db".
+func WithServiceName(name string) DDOption {
+	return func(h *DatadogHook) {
+		h.serviceName = name
+	}
+}
+
+// WithSanitizeQuery controls whether sql.query is recorded via
+// UnformattedQuery, stripping literal parameter values from the reported
+// query. It defaults to true; pass false to record the fully formatted
+// query instead, e.g. for local debugging. INSERT queries are always
+// recorded unformatted, whatever this option says.
+func WithSanitizeQuery(enabled bool) DDOption {
+	return func(h *DatadogHook) {
+		h.rawStatement = !enabled
+	}
+}
+
+// NewDatadogHook returns a DatadogHook ready to be added to a *pg.DB.
+func NewDatadogHook(opts ...DDOption) *DatadogHook {
+	h := &DatadogHook{serviceName: "postgres.db"}
+	for _, opt := range opts {
+		opt(h)
+	}
+	return h
+}
+
+func (h *DatadogHook) BeforeQuery(ctx context.Context, evt *pg.QueryEvent) (context.Context, error) {
+	var operation orm.QueryOp
+	if v, ok := evt.Query.(queryOperation); ok {
+		operation = v.Operation()
+	}
+
+	spanOpts := []ddtrace.StartSpanOption{
+		tracer.ServiceName(h.serviceName),
+		tracer.SpanType(ext.SpanTypeSQL),
+	}
+	if operation != "" {
+		spanOpts = append(spanOpts, tracer.ResourceName(string(operation)))
+	}
+	if db, ok := evt.DB.(*pg.DB); ok {
+		opt := db.Options()
+		spanOpts = append(spanOpts,
+			tracer.Tag("out.db", opt.Database),
+			tracer.Tag("out.host", opt.Addr),
+		)
+	}
+
+	span, ctx := tracer.StartSpanFromContext(ctx, "postgres.query", spanOpts...)
+
+	if evt.Stash == nil {
+		evt.Stash = make(map[interface{}]interface{})
+	}
+	evt.Stash[ddSpanKey{}] = span
+
+	return ctx, nil
+}
+
+func (h *DatadogHook) AfterQuery(ctx context.Context, evt *pg.QueryEvent) error {
+	span, ok := evt.Stash[ddSpanKey{}].(ddtrace.Span)
+	if !ok {
+		return nil
+	}
+	delete(evt.Stash, ddSpanKey{})
+
+	var operation orm.QueryOp
+	if v, ok := evt.Query.(queryOperation); ok {
+		operation = v.Operation()
+	}
+
+	var query string
+	if !h.rawStatement || operation == orm.InsertOp {
+		b, err := evt.UnformattedQuery()
+		if err != nil {
+			span.Finish(tracer.WithError(err))
+			return err
+		}
+		query = string(b)
+	} else {
+		b, err := evt.FormattedQuery()
+		if err != nil {
+			span.Finish(tracer.WithError(err))
+			return err
+		}
+		query = string(b)
+	}
+
+	const queryLimit = 5000
+	if len(query) > queryLimit {
+		query = query[:queryLimit]
+	}
+	span.SetTag("sql.query", query)
+
+	if operation == "" {
+		span.SetTag(ext.ResourceName, fallbackResourceName(query))
+	}
+
+	if evt.Err != nil {
+		switch evt.Err {
+		case pg.ErrNoRows, pg.ErrMultiRows:
+			span.Finish()
+		default:
+			span.Finish(tracer.WithError(evt.Err))
+		}
+		return nil
+	}
+
+	if evt.Result != nil {
+		numRow := evt.Result.RowsAffected()
+		if numRow == 0 {
+			numRow = evt.Result.RowsReturned()
+		}
+		span.SetTag("db.rows_affected", numRow)
+	}
+	span.Finish()
+
+	return nil
+}
+
+// fallbackResourceName derives a resource name from the first word of query,
+// truncated to resourceLimit, for queries whose operation can't be
+// determined from the query builder.
+func fallbackResourceName(query string) string {
+	const resourceLimit = 20
+
+	resource := query
+	if idx := strings.IndexByte(query, ' '); idx >= 0 {
+		resource = query[:idx]
+	}
+	if len(resource) > resourceLimit {
+		resource = resource[:resourceLimit]
+	}
+	return strings.TrimSpace(resource)
+}
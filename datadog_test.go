@@ -0,0 +1,25 @@
+package pgext
+
+import "testing"
+
+func TestFallbackResourceName(t *testing.T) {
+	tests := []struct {
+		name  string
+		query string
+		want  string
+	}{
+		{"short query", "begin", "begin"},
+		{"multi word", "select 1", "select"},
+		{"no space over limit", "abcdefghijklmnopqrstuvwxyz", "abcdefghijklmnopqrst"},
+		{"word over limit", "abcdefghijklmnopqrstuvwxyz and more", "abcdefghijklmnopqrst"},
+		{"leading space trimmed after truncation", " select 1", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := fallbackResourceName(tt.query); got != tt.want {
+				t.Errorf("fallbackResourceName(%q) = %q, want %q", tt.query, got, tt.want)
+			}
+		})
+	}
+}
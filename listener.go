@@ -0,0 +1,75 @@
+package pgext
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/go-pg/pg/v10"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// ListenerHook wraps a *pg.Listener so every NOTIFY received through its
+// Receive method shows up as a span alongside query spans, rather than being
+// invisible to tracing. Notifications read through the embedded Listener's
+// other methods, such as Channel or ReceiveTimeout, are not traced.
+type ListenerHook struct {
+	*pg.Listener
+}
+
+// NewListenerHook wraps ln so its Receive calls are traced.
+func NewListenerHook(ln *pg.Listener) *ListenerHook {
+	return &ListenerHook{Listener: ln}
+}
+
+// notificationTimestampSep separates an optional RFC3339Nano send timestamp
+// from the rest of a NOTIFY payload. A colon would be the more obvious
+// choice but appears inside RFC3339Nano itself (time-of-day and UTC
+// offset), so it can't delimit a prefix reliably.
+const notificationTimestampSep = '|'
+
+// Receive waits for a notification and records it as a span with the
+// channel name and payload size as attributes. If payload starts with an
+// RFC3339Nano timestamp followed by notificationTimestampSep - a convention
+// some senders use so consumers can measure delivery latency - that prefix
+// is parsed into a db.notification.latency_ms attribute and stripped from
+// the returned payload.
+func (h *ListenerHook) Receive(ctx context.Context) (channel, payload string, err error) {
+	channel, payload, err = h.Listener.Receive(ctx)
+	if err != nil {
+		return channel, payload, err
+	}
+
+	_, span := otelTracer.Start(ctx, "postgres.notify", trace.WithSpanKind(trace.SpanKindConsumer))
+	defer span.End()
+
+	attrs := []attribute.KeyValue{
+		attribute.String("db.notification.channel", channel),
+		attribute.Int("db.notification.payload_size", len(payload)),
+	}
+
+	if sentAt, rest, ok := splitNotificationTimestamp(payload); ok {
+		attrs = append(attrs, attribute.Int64("db.notification.latency_ms", time.Since(sentAt).Milliseconds()))
+		payload = rest
+	}
+
+	span.SetAttributes(attrs...)
+
+	return channel, payload, nil
+}
+
+// splitNotificationTimestamp extracts a leading "<RFC3339Nano><sep>" prefix
+// from payload, if present, returning the parsed send time, the remaining
+// payload, and whether a prefix was found.
+func splitNotificationTimestamp(payload string) (sentAt time.Time, rest string, ok bool) {
+	idx := strings.IndexByte(payload, notificationTimestampSep)
+	if idx < 0 {
+		return time.Time{}, payload, false
+	}
+	sentAt, err := time.Parse(time.RFC3339Nano, payload[:idx])
+	if err != nil {
+		return time.Time{}, payload, false
+	}
+	return sentAt, payload[idx+1:], true
+}
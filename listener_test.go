@@ -0,0 +1,39 @@
+package pgext
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSplitNotificationTimestamp(t *testing.T) {
+	sentAt := time.Date(2024, 3, 15, 9, 30, 0, 0, time.UTC)
+	prefixed := sentAt.Format(time.RFC3339Nano) + "|hello"
+
+	tests := []struct {
+		name       string
+		payload    string
+		wantRest   string
+		wantOK     bool
+		wantSentAt time.Time
+	}{
+		{"valid prefix", prefixed, "hello", true, sentAt},
+		{"no separator", "hello", "hello", false, time.Time{}},
+		{"unparseable prefix", "not-a-time|hello", "not-a-time|hello", false, time.Time{}},
+		{"empty payload", "", "", false, time.Time{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gotSentAt, gotRest, gotOK := splitNotificationTimestamp(tt.payload)
+			if gotOK != tt.wantOK {
+				t.Fatalf("splitNotificationTimestamp(%q) ok = %v, want %v", tt.payload, gotOK, tt.wantOK)
+			}
+			if gotRest != tt.wantRest {
+				t.Errorf("splitNotificationTimestamp(%q) rest = %q, want %q", tt.payload, gotRest, tt.wantRest)
+			}
+			if tt.wantOK && !gotSentAt.Equal(tt.wantSentAt) {
+				t.Errorf("splitNotificationTimestamp(%q) sentAt = %v, want %v", tt.payload, gotSentAt, tt.wantSentAt)
+			}
+		})
+	}
+}
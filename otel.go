@@ -2,52 +2,209 @@ package pgext
 
 import (
 	"context"
-	"runtime"
+	"fmt"
+	"net"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/go-pg/pg/v10"
 	"github.com/go-pg/pg/v10/orm"
-	"go.opentelemetry.io/otel/api/global"
-	"go.opentelemetry.io/otel/api/trace"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
 	"go.opentelemetry.io/otel/codes"
-	"go.opentelemetry.io/otel/label"
+	"go.opentelemetry.io/otel/metric"
+	semconv "go.opentelemetry.io/otel/semconv/v1.21.0"
+	"go.opentelemetry.io/otel/trace"
 )
 
-var tracer = global.Tracer("github.com/go-pg/pg")
+var otelTracer = otel.Tracer("github.com/go-pg/pg")
 
 type queryOperation interface {
 	Operation() orm.QueryOp
 }
 
+type queryStartTimeKey struct{}
+
 // OpenTelemetryHook is a pg.QueryHook that adds OpenTemetry instrumentation.
-type OpenTelemetryHook struct{}
+type OpenTelemetryHook struct {
+	queryDuration metric.Float64Histogram
+
+	rawStatement        bool
+	formattedParameters bool
+}
 
 var _ pg.QueryHook = (*OpenTelemetryHook)(nil)
 
+// Option configures an OpenTelemetryHook.
+type Option func(*OpenTelemetryHook)
+
+// WithSanitizeStatement controls whether db.statement is recorded via
+// UnformattedQuery, stripping literal parameter values from the reported
+// statement. It defaults to true; pass false to record the fully formatted
+// query instead, e.g. for local debugging. INSERT queries are always
+// recorded unformatted, whatever this option says.
+func WithSanitizeStatement(enabled bool) Option {
+	return func(h *OpenTelemetryHook) {
+		h.rawStatement = !enabled
+	}
+}
+
+// WithFormattedParameters additionally records each query argument as an
+// indexed db.query.parameter.<i> attribute. It defaults to false, since
+// parameters are subject to the same PII concerns as an unsanitized
+// statement.
+func WithFormattedParameters(enabled bool) Option {
+	return func(h *OpenTelemetryHook) {
+		h.formattedParameters = enabled
+	}
+}
+
+// NewOpenTelemetryHook returns an OpenTelemetryHook with metrics initialized
+// against db. It is equivalent to constructing an OpenTelemetryHook and
+// calling Init(db) on it.
+func NewOpenTelemetryHook(db *pg.DB, opts ...Option) (*OpenTelemetryHook, error) {
+	h := new(OpenTelemetryHook)
+	for _, opt := range opts {
+		opt(h)
+	}
+	if err := h.Init(db); err != nil {
+		return nil, err
+	}
+	return h, nil
+}
+
+// Init registers the query duration histogram and a periodic callback that
+// observes db's connection pool stats against the "github.com/go-pg/pg"
+// meter, and wraps db's OnConnect callback so each new physical connection
+// is marked by a postgres.connect span. The span starts once the connection
+// has been dialled and authenticated and covers only the wrapped OnConnect
+// callback, not the dial or authentication. It must be called once before
+// the hook starts receiving queries.
+//
+// A per-query db.conn.wait_ms attribute is not implemented: pg.QueryHook
+// only brackets query execution, not the pool checkout that precedes it, so
+// that wait time isn't observable from a QueryHook alone.
+func (h *OpenTelemetryHook) Init(db *pg.DB) error {
+	opt := db.Options()
+	onConnect := opt.OnConnect
+	opt.OnConnect = func(ctx context.Context, cn *pg.Conn) error {
+		ctx, span := otelTracer.Start(ctx, "postgres.connect", trace.WithSpanKind(trace.SpanKindClient))
+		defer span.End()
+
+		if onConnect == nil {
+			return nil
+		}
+		if err := onConnect(ctx, cn); err != nil {
+			span.SetStatus(codes.Error, err.Error())
+			span.RecordError(err)
+			return err
+		}
+		return nil
+	}
+
+	meter := otel.Meter("github.com/go-pg/pg")
+
+	queryDuration, err := meter.Float64Histogram(
+		"db.client.query.duration",
+		metric.WithDescription("Duration of database client queries"),
+		metric.WithUnit("ms"),
+	)
+	if err != nil {
+		return err
+	}
+	h.queryDuration = queryDuration
+
+	hits, err := meter.Int64ObservableGauge("db.client.connections.hits",
+		metric.WithDescription("The number of times a free connection was found in the pool"))
+	if err != nil {
+		return err
+	}
+	misses, err := meter.Int64ObservableGauge("db.client.connections.misses",
+		metric.WithDescription("The number of times a free connection was not found in the pool"))
+	if err != nil {
+		return err
+	}
+	timeouts, err := meter.Int64ObservableGauge("db.client.connections.timeouts",
+		metric.WithDescription("The number of times a wait timeout occurred"))
+	if err != nil {
+		return err
+	}
+	totalConns, err := meter.Int64ObservableGauge("db.client.connections.total",
+		metric.WithDescription("The number of total connections in the pool"))
+	if err != nil {
+		return err
+	}
+	idleConns, err := meter.Int64ObservableGauge("db.client.connections.idle",
+		metric.WithDescription("The number of idle connections in the pool"))
+	if err != nil {
+		return err
+	}
+
+	_, err = meter.RegisterCallback(func(_ context.Context, o metric.Observer) error {
+		stats := db.PoolStats()
+		o.ObserveInt64(hits, int64(stats.Hits))
+		o.ObserveInt64(misses, int64(stats.Misses))
+		o.ObserveInt64(timeouts, int64(stats.Timeouts))
+		o.ObserveInt64(totalConns, int64(stats.TotalConns))
+		o.ObserveInt64(idleConns, int64(stats.IdleConns))
+		return nil
+	}, hits, misses, timeouts, totalConns, idleConns)
+	return err
+}
+
 func (h OpenTelemetryHook) BeforeQuery(ctx context.Context, evt *pg.QueryEvent) (context.Context, error) {
+	ctx = context.WithValue(ctx, queryStartTimeKey{}, time.Now())
+
 	if !trace.SpanFromContext(ctx).IsRecording() {
 		return ctx, nil
 	}
 
-	ctx, _ = tracer.Start(ctx, "")
+	ctx, _ = otelTracer.Start(ctx, "", trace.WithSpanKind(trace.SpanKindClient))
 	return ctx, nil
 }
 
 func (h OpenTelemetryHook) AfterQuery(ctx context.Context, evt *pg.QueryEvent) error {
+	var operation orm.QueryOp
+	if v, ok := evt.Query.(queryOperation); ok {
+		operation = v.Operation()
+	}
+	name := string(operation)
+
+	errClass := "ok"
+	switch evt.Err {
+	case nil:
+	case pg.ErrNoRows, pg.ErrMultiRows:
+		errClass = "no_rows"
+	default:
+		errClass = "error"
+	}
+
+	var dbName string
+	if db, ok := evt.DB.(*pg.DB); ok {
+		dbName = db.Options().Database
+	}
+
+	if h.queryDuration != nil {
+		if start, ok := ctx.Value(queryStartTimeKey{}).(time.Time); ok {
+			h.queryDuration.Record(ctx, float64(time.Since(start).Milliseconds()),
+				metric.WithAttributes(
+					attribute.String("operation", name),
+					attribute.String("db.name", dbName),
+					attribute.String("error_class", errClass),
+				),
+			)
+		}
+	}
+
 	span := trace.SpanFromContext(ctx)
 	if !span.IsRecording() {
 		return nil
 	}
 	defer span.End()
 
-	var operation orm.QueryOp
-
-	if v, ok := evt.Query.(queryOperation); ok {
-		operation = v.Operation()
-	}
-
 	var query string
-	if operation == orm.InsertOp {
+	if !h.rawStatement || operation == orm.InsertOp {
 		b, err := evt.UnformattedQuery()
 		if err != nil {
 			return err
@@ -61,8 +218,8 @@ func (h OpenTelemetryHook) AfterQuery(ctx context.Context, evt *pg.QueryEvent) e
 		query = string(b)
 	}
 
-	if operation != "" {
-		span.SetName(string(operation))
+	if name != "" {
+		span.SetName(name)
 	} else if idx := strings.IndexByte(query, ' '); idx >= 0 {
 		if idx > 20 {
 			idx = 20
@@ -75,41 +232,51 @@ func (h OpenTelemetryHook) AfterQuery(ctx context.Context, evt *pg.QueryEvent) e
 		query = query[:queryLimit]
 	}
 
-	fn, file, line := funcFileLine("github.com/go-pg/pg")
-
-	attrs := make([]label.KeyValue, 0, 10)
+	attrs := make([]attribute.KeyValue, 0, 8)
 	attrs = append(attrs,
-		label.String("db.system", "postgres"),
-		label.String("db.statement", query),
-
-		label.String("frame.func", fn),
-		label.String("frame.file", file),
-		label.Int("frame.line", line),
+		semconv.DBSystemPostgreSQL,
+		semconv.DBStatementKey.String(query),
 	)
+	if name != "" {
+		attrs = append(attrs, semconv.DBOperationKey.String(name))
+	}
 
 	if db, ok := evt.DB.(*pg.DB); ok {
 		opt := db.Options()
 		attrs = append(attrs,
-			label.String("db.connection_string", opt.Addr),
-			label.String("db.user", opt.User),
-			label.String("db.name", opt.Database),
+			semconv.DBNameKey.String(opt.Database),
+			semconv.DBUserKey.String(opt.User),
 		)
+		if host, portStr, err := net.SplitHostPort(opt.Addr); err == nil {
+			attrs = append(attrs, semconv.ServerAddressKey.String(host))
+			if port, err := strconv.Atoi(portStr); err == nil {
+				attrs = append(attrs, semconv.ServerPortKey.Int(port))
+			}
+		}
 	}
 
-	if evt.Err != nil {
-		switch evt.Err {
-		case pg.ErrNoRows, pg.ErrMultiRows:
-			span.SetStatus(codes.NotFound, "")
-		default:
-			span.SetStatus(codes.Internal, "")
-			span.RecordError(ctx, evt.Err)
+	if h.formattedParameters {
+		for i, param := range evt.Params {
+			attrs = append(attrs, attribute.String(fmt.Sprintf("db.query.parameter.%d", i), formatParam(param)))
 		}
-	} else if evt.Result != nil {
-		numRow := evt.Result.RowsAffected()
-		if numRow == 0 {
-			numRow = evt.Result.RowsReturned()
+	}
+
+	switch evt.Err {
+	case nil:
+		if evt.Result != nil {
+			numRow := evt.Result.RowsAffected()
+			if numRow == 0 {
+				numRow = evt.Result.RowsReturned()
+			}
+			attrs = append(attrs, attribute.Int("db.rows_affected", numRow))
 		}
-		attrs = append(attrs, label.Int("db.rows_affected", numRow))
+	case pg.ErrNoRows, pg.ErrMultiRows:
+		// Leave span status Unset: a routine no-rows/multi-rows outcome is
+		// not a failure, matching the metrics "no_rows" classification
+		// above and DatadogHook's equivalent handling.
+	default:
+		span.SetStatus(codes.Error, evt.Err.Error())
+		span.RecordError(evt.Err)
 	}
 
 	span.SetAttributes(attrs...)
@@ -117,28 +284,22 @@ func (h OpenTelemetryHook) AfterQuery(ctx context.Context, evt *pg.QueryEvent) e
 	return nil
 }
 
-func funcFileLine(pkg string) (string, string, int) {
-	const depth = 16
-	var pcs [depth]uintptr
-	n := runtime.Callers(3, pcs[:])
-	ff := runtime.CallersFrames(pcs[:n])
-
-	var fn, file string
-	var line int
-	for {
-		f, ok := ff.Next()
-		if !ok {
-			break
-		}
-		fn, file, line = f.Function, f.File, f.Line
-		if !strings.Contains(fn, pkg) {
-			break
-		}
-	}
+// formatParam renders a query argument for the db.query.parameter.<i>
+// attribute, truncating long strings and reporting []byte by length only.
+func formatParam(v interface{}) string {
+	const strLimit = 100
 
-	if ind := strings.LastIndexByte(fn, '/'); ind != -1 {
-		fn = fn[ind+1:]
+	switch v := v.(type) {
+	case nil:
+		return "<nil>"
+	case []byte:
+		return fmt.Sprintf("[%d bytes]", len(v))
+	case string:
+		if len(v) > strLimit {
+			return v[:strLimit] + "..."
+		}
+		return v
+	default:
+		return fmt.Sprint(v)
 	}
-
-	return fn, file, line
 }
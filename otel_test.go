@@ -0,0 +1,32 @@
+package pgext
+
+import "testing"
+
+func TestFormatParam(t *testing.T) {
+	longString := make([]byte, 150)
+	for i := range longString {
+		longString[i] = 'a'
+	}
+
+	tests := []struct {
+		name string
+		in   interface{}
+		want string
+	}{
+		{"nil", nil, "<nil>"},
+		{"bytes", []byte("hello"), "[5 bytes]"},
+		{"empty bytes", []byte{}, "[0 bytes]"},
+		{"short string", "hello", "hello"},
+		{"string at limit", string(longString[:100]), string(longString[:100])},
+		{"long string", string(longString), string(longString[:100]) + "..."},
+		{"int", 42, "42"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := formatParam(tt.in); got != tt.want {
+				t.Errorf("formatParam(%#v) = %q, want %q", tt.in, got, tt.want)
+			}
+		})
+	}
+}
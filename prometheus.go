@@ -0,0 +1,198 @@
+package pgext
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/go-pg/pg/v10"
+	"github.com/go-pg/pg/v10/orm"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// PrometheusHook is a pg.QueryHook that exports query and connection pool
+// metrics to a prometheus.Registerer, for users who scrape Prometheus
+// directly and don't want to pull in the OpenTelemetry SDK.
+type PrometheusHook struct {
+	queryDuration *prometheus.HistogramVec
+	queryErrors   *prometheus.CounterVec
+
+	poolHits       prometheus.Gauge
+	poolMisses     prometheus.Gauge
+	poolTimeouts   prometheus.Gauge
+	poolTotalConns prometheus.Gauge
+	poolIdleConns  prometheus.Gauge
+
+	reg        prometheus.Registerer
+	collectors []prometheus.Collector
+
+	stop      chan struct{}
+	closeOnce sync.Once
+}
+
+var _ pg.QueryHook = (*PrometheusHook)(nil)
+
+type promQueryStartTimeKey struct{}
+
+type promOptions struct {
+	namespace    string
+	pollInterval time.Duration
+}
+
+// PromOption configures a PrometheusHook.
+type PromOption func(*promOptions)
+
+// WithNamespace sets the Prometheus namespace prefixed to every metric
+// name. It defaults to empty.
+func WithNamespace(namespace string) PromOption {
+	return func(o *promOptions) {
+		o.namespace = namespace
+	}
+}
+
+// WithPollInterval sets how often connection pool stats are observed. It
+// defaults to 10 seconds.
+func WithPollInterval(interval time.Duration) PromOption {
+	return func(o *promOptions) {
+		o.pollInterval = interval
+	}
+}
+
+// NewPrometheusHook registers query and connection pool collectors for db
+// on reg and returns a PrometheusHook ready to be added to db. Call Close
+// when db is no longer used to stop the background pool-stats poller.
+func NewPrometheusHook(db *pg.DB, reg prometheus.Registerer, opts ...PromOption) (*PrometheusHook, error) {
+	o := promOptions{pollInterval: 10 * time.Second}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	if o.pollInterval <= 0 {
+		o.pollInterval = 10 * time.Second
+	}
+
+	h := &PrometheusHook{
+		queryDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: o.namespace,
+			Name:      "pg_query_duration_seconds",
+			Help:      "Duration of database queries in seconds",
+		}, []string{"operation", "status"}),
+		queryErrors: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: o.namespace,
+			Name:      "pg_query_errors_total",
+			Help:      "Total number of queries that returned an error",
+		}, []string{"operation"}),
+		poolHits: prometheus.NewGauge(prometheus.GaugeOpts{
+			Namespace: o.namespace,
+			Name:      "pg_pool_hits",
+			Help:      "Number of times a free connection was found in the pool",
+		}),
+		poolMisses: prometheus.NewGauge(prometheus.GaugeOpts{
+			Namespace: o.namespace,
+			Name:      "pg_pool_misses",
+			Help:      "Number of times a free connection was not found in the pool",
+		}),
+		poolTimeouts: prometheus.NewGauge(prometheus.GaugeOpts{
+			Namespace: o.namespace,
+			Name:      "pg_pool_timeouts",
+			Help:      "Number of times a wait timeout occurred",
+		}),
+		poolTotalConns: prometheus.NewGauge(prometheus.GaugeOpts{
+			Namespace: o.namespace,
+			Name:      "pg_pool_total_conns",
+			Help:      "Number of total connections in the pool",
+		}),
+		poolIdleConns: prometheus.NewGauge(prometheus.GaugeOpts{
+			Namespace: o.namespace,
+			Name:      "pg_pool_idle_conns",
+			Help:      "Number of idle connections in the pool",
+		}),
+		stop: make(chan struct{}),
+	}
+
+	h.reg = reg
+	h.collectors = []prometheus.Collector{
+		h.queryDuration,
+		h.queryErrors,
+		h.poolHits,
+		h.poolMisses,
+		h.poolTimeouts,
+		h.poolTotalConns,
+		h.poolIdleConns,
+	}
+	for i, c := range h.collectors {
+		if err := reg.Register(c); err != nil {
+			for _, registered := range h.collectors[:i] {
+				reg.Unregister(registered)
+			}
+			return nil, err
+		}
+	}
+
+	go h.pollPoolStats(db, o.pollInterval)
+
+	return h, nil
+}
+
+func (h *PrometheusHook) pollPoolStats(db *pg.DB, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			stats := db.PoolStats()
+			h.poolHits.Set(float64(stats.Hits))
+			h.poolMisses.Set(float64(stats.Misses))
+			h.poolTimeouts.Set(float64(stats.Timeouts))
+			h.poolTotalConns.Set(float64(stats.TotalConns))
+			h.poolIdleConns.Set(float64(stats.IdleConns))
+		case <-h.stop:
+			return
+		}
+	}
+}
+
+// Close stops the background goroutine that polls connection pool stats and
+// unregisters its collectors from the Registerer passed to
+// NewPrometheusHook. It is safe to call more than once.
+func (h *PrometheusHook) Close() error {
+	h.closeOnce.Do(func() {
+		close(h.stop)
+		for _, c := range h.collectors {
+			h.reg.Unregister(c)
+		}
+	})
+	return nil
+}
+
+func (h *PrometheusHook) BeforeQuery(ctx context.Context, evt *pg.QueryEvent) (context.Context, error) {
+	return context.WithValue(ctx, promQueryStartTimeKey{}, time.Now()), nil
+}
+
+func (h *PrometheusHook) AfterQuery(ctx context.Context, evt *pg.QueryEvent) error {
+	var operation orm.QueryOp
+	if v, ok := evt.Query.(queryOperation); ok {
+		operation = v.Operation()
+	}
+
+	name := string(operation)
+	if name == "" {
+		name = "other"
+	}
+
+	status := "ok"
+	switch evt.Err {
+	case nil:
+	case pg.ErrNoRows, pg.ErrMultiRows:
+		status = "no_rows"
+	default:
+		status = "error"
+		h.queryErrors.WithLabelValues(name).Inc()
+	}
+
+	if start, ok := ctx.Value(promQueryStartTimeKey{}).(time.Time); ok {
+		h.queryDuration.WithLabelValues(name, status).Observe(time.Since(start).Seconds())
+	}
+
+	return nil
+}
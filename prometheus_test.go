@@ -0,0 +1,72 @@
+package pgext
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/go-pg/pg/v10"
+	"github.com/go-pg/pg/v10/orm"
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+)
+
+type promTestOp orm.QueryOp
+
+func (o promTestOp) Operation() orm.QueryOp {
+	return orm.QueryOp(o)
+}
+
+func TestPrometheusHookAfterQuery(t *testing.T) {
+	tests := []struct {
+		name          string
+		op            orm.QueryOp
+		err           error
+		wantOperation string
+		wantStatus    string
+		wantErrors    float64
+	}{
+		{"select ok", orm.SelectOp, nil, "SELECT", "ok", 0},
+		{"no rows", orm.SelectOp, pg.ErrNoRows, "SELECT", "no_rows", 0},
+		{"multi rows", orm.SelectOp, pg.ErrMultiRows, "SELECT", "no_rows", 0},
+		{"error", orm.SelectOp, errors.New("boom"), "SELECT", "error", 1},
+		{"unknown operation", "", nil, "other", "ok", 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			reg := prometheus.NewRegistry()
+			h, err := NewPrometheusHook(&pg.DB{}, reg)
+			if err != nil {
+				t.Fatalf("NewPrometheusHook() error = %v", err)
+			}
+			defer h.Close()
+
+			evt := &pg.QueryEvent{Query: promTestOp(tt.op), Err: tt.err}
+
+			ctx, err := h.BeforeQuery(context.Background(), evt)
+			if err != nil {
+				t.Fatalf("BeforeQuery() error = %v", err)
+			}
+			if err := h.AfterQuery(ctx, evt); err != nil {
+				t.Fatalf("AfterQuery() error = %v", err)
+			}
+
+			var m dto.Metric
+			if err := h.queryDuration.WithLabelValues(tt.wantOperation, tt.wantStatus).(prometheus.Histogram).Write(&m); err != nil {
+				t.Fatalf("write histogram: %v", err)
+			}
+			if got := m.GetHistogram().GetSampleCount(); got != 1 {
+				t.Errorf("queryDuration(%q, %q) sample count = %d, want 1", tt.wantOperation, tt.wantStatus, got)
+			}
+
+			var errM dto.Metric
+			if err := h.queryErrors.WithLabelValues(tt.wantOperation).Write(&errM); err != nil {
+				t.Fatalf("write counter: %v", err)
+			}
+			if got := errM.GetCounter().GetValue(); got != tt.wantErrors {
+				t.Errorf("queryErrors(%q) = %v, want %v", tt.wantOperation, got, tt.wantErrors)
+			}
+		})
+	}
+}